@@ -0,0 +1,238 @@
+package azblob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/benbjohnson/litestream"
+)
+
+// Default replica settings.
+const (
+	DefaultRetention              = 24 * time.Hour
+	DefaultRetentionCheckInterval = 1 * time.Hour
+	DefaultSyncInterval           = 1 * time.Second
+)
+
+// Replica is a client for writing a litestream replica to Azure Blob Storage.
+type Replica struct {
+	db   *litestream.DB
+	name string
+
+	mu           sync.Mutex
+	containerURL azblob.ContainerURL
+
+	// Storage account credentials and target container.
+	AccountName string
+	AccountKey  string
+	Container   string
+
+	// Optional prefix within the container.
+	Path string
+
+	// Frequency to check for new data to replicate.
+	SyncInterval time.Duration
+
+	// Time to keep snapshots and WAL files before they are allowed to be deleted.
+	Retention time.Duration
+
+	// Time between checks for retention enforcement.
+	RetentionCheckInterval time.Duration
+}
+
+// NewReplica returns a new instance of Replica.
+func NewReplica(db *litestream.DB, name string) *Replica {
+	return &Replica{
+		db:                     db,
+		name:                   name,
+		SyncInterval:           DefaultSyncInterval,
+		Retention:              DefaultRetention,
+		RetentionCheckInterval: DefaultRetentionCheckInterval,
+	}
+}
+
+// Name returns the name of the replica, if set.
+func (r *Replica) Name() string { return r.name }
+
+// DB returns the database the replica is attached to.
+func (r *Replica) DB() *litestream.DB { return r.db }
+
+// ContainerURL lazily initializes and returns the Azure container URL client.
+func (r *Replica) ContainerURL() (azblob.ContainerURL, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if (r.containerURL != azblob.ContainerURL{}) {
+		return r.containerURL, nil
+	}
+
+	credential, err := azblob.NewSharedKeyCredential(r.AccountName, r.AccountKey)
+	if err != nil {
+		return azblob.ContainerURL{}, fmt.Errorf("azblob: cannot create credential: %w", err)
+	}
+
+	u, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", r.AccountName, r.Container))
+	if err != nil {
+		return azblob.ContainerURL{}, fmt.Errorf("azblob: cannot parse container url: %w", err)
+	}
+
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+	r.containerURL = azblob.NewContainerURL(*u, pipeline)
+	return r.containerURL, nil
+}
+
+// blobKey returns the full blob name for a given relative key, rooted under Path.
+func (r *Replica) blobKey(key string) string {
+	if r.Path == "" {
+		return key
+	}
+	return path.Join(r.Path, key)
+}
+
+// WriteSnapshot uploads a snapshot for generation at index to Azure Blob Storage.
+func (r *Replica) WriteSnapshot(ctx context.Context, generation string, index int, rd io.Reader) error {
+	containerURL, err := r.ContainerURL()
+	if err != nil {
+		return err
+	}
+
+	buf, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return fmt.Errorf("azblob: cannot read snapshot: %w", err)
+	}
+
+	key := r.blobKey(path.Join("generations", generation, "snapshots", fmt.Sprintf("%08x.snapshot.lz4", index)))
+	blobURL := containerURL.NewBlockBlobURL(key)
+	if _, err := blobURL.Upload(ctx, bytes.NewReader(buf), azblob.BlobHTTPHeaders{}, azblob.Metadata{}, azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, nil, azblob.ClientProvidedKeyOptions{}, azblob.ImmutabilityPolicyOptions{}); err != nil {
+		return fmt.Errorf("azblob: cannot write snapshot: %w", err)
+	}
+	return nil
+}
+
+// WriteWALSegment uploads a single WAL segment to Azure Blob Storage.
+func (r *Replica) WriteWALSegment(ctx context.Context, generation string, index int, offset int64, rd io.Reader) error {
+	containerURL, err := r.ContainerURL()
+	if err != nil {
+		return err
+	}
+
+	buf, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return fmt.Errorf("azblob: cannot read wal segment: %w", err)
+	}
+
+	key := r.blobKey(path.Join("generations", generation, "wal", fmt.Sprintf("%08x_%08x.wal.lz4", index, offset)))
+	blobURL := containerURL.NewBlockBlobURL(key)
+	if _, err := blobURL.Upload(ctx, bytes.NewReader(buf), azblob.BlobHTTPHeaders{}, azblob.Metadata{}, azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, nil, azblob.ClientProvidedKeyOptions{}, azblob.ImmutabilityPolicyOptions{}); err != nil {
+		return fmt.Errorf("azblob: cannot write wal segment: %w", err)
+	}
+	return nil
+}
+
+// Generations returns a list of available generation names.
+func (r *Replica) Generations(ctx context.Context) ([]string, error) {
+	containerURL, err := r.ContainerURL()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := r.blobKey("generations") + "/"
+	var generations []string
+	seen := make(map[string]struct{})
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		resp, err := containerURL.ListBlobsHierarchySegment(ctx, marker, "/", azblob.ListBlobsSegmentOptions{Prefix: prefix})
+		if err != nil {
+			return nil, fmt.Errorf("azblob: cannot list generations: %w", err)
+		}
+		for _, blobPrefix := range resp.Segment.BlobPrefixes {
+			generation := strings.Trim(strings.TrimPrefix(blobPrefix.Name, prefix), "/")
+			if _, ok := seen[generation]; !ok {
+				seen[generation] = struct{}{}
+				generations = append(generations, generation)
+			}
+		}
+		marker = resp.NextMarker
+	}
+	return generations, nil
+}
+
+// EnforceRetention removes generations whose newest blob is older than Retention.
+func (r *Replica) EnforceRetention(ctx context.Context) error {
+	generations, err := r.Generations(ctx)
+	if err != nil {
+		return err
+	}
+
+	containerURL, err := r.ContainerURL()
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-r.Retention)
+	for _, generation := range generations {
+		prefix := r.blobKey(path.Join("generations", generation)) + "/"
+		var newest time.Time
+		var names []string
+		for marker := (azblob.Marker{}); marker.NotDone(); {
+			resp, err := containerURL.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: prefix})
+			if err != nil {
+				return fmt.Errorf("azblob: cannot enumerate generation %s: %w", generation, err)
+			}
+			for _, item := range resp.Segment.BlobItems {
+				if item.Properties.LastModified.After(newest) {
+					newest = item.Properties.LastModified
+				}
+				names = append(names, item.Name)
+			}
+			marker = resp.NextMarker
+		}
+
+		if newest.Before(cutoff) {
+			for _, name := range names {
+				if _, err := containerURL.NewBlobURL(name).Delete(ctx, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{}); err != nil {
+					return fmt.Errorf("azblob: cannot delete blob %s: %w", name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// WALReader returns a reader for a single WAL segment at the given generation, index, and offset.
+func (r *Replica) WALReader(ctx context.Context, generation string, index int, offset int64) (io.ReadCloser, error) {
+	containerURL, err := r.ContainerURL()
+	if err != nil {
+		return nil, err
+	}
+
+	key := r.blobKey(path.Join("generations", generation, "wal", fmt.Sprintf("%08x_%08x.wal.lz4", index, offset)))
+	resp, err := containerURL.NewBlobURL(key).Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("azblob: cannot download wal segment: %w", err)
+	}
+	return resp.Body(azblob.RetryReaderOptions{}), nil
+}
+
+// SnapshotReader returns a reader for the snapshot at the given generation and index.
+func (r *Replica) SnapshotReader(ctx context.Context, generation string, index int) (io.ReadCloser, error) {
+	containerURL, err := r.ContainerURL()
+	if err != nil {
+		return nil, err
+	}
+
+	key := r.blobKey(path.Join("generations", generation, "snapshots", fmt.Sprintf("%08x.snapshot.lz4", index)))
+	resp, err := containerURL.NewBlobURL(key).Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("azblob: cannot download snapshot: %w", err)
+	}
+	return resp.Body(azblob.RetryReaderOptions{}), nil
+}
@@ -0,0 +1,223 @@
+package b2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/kurin/blazer/b2"
+)
+
+// Default replica settings.
+const (
+	DefaultRetention              = 24 * time.Hour
+	DefaultRetentionCheckInterval = 1 * time.Hour
+	DefaultSyncInterval           = 1 * time.Second
+)
+
+// Replica is a client for writing a litestream replica to Backblaze B2.
+type Replica struct {
+	db   *litestream.DB
+	name string
+
+	mu     sync.Mutex
+	bucket *b2.Bucket
+
+	// Backblaze account credentials and target bucket.
+	AccountID      string
+	ApplicationKey string
+	Bucket         string
+
+	// Optional prefix within the bucket.
+	Path string
+
+	// Frequency to check for new data to replicate.
+	SyncInterval time.Duration
+
+	// Time to keep snapshots and WAL files before they are allowed to be deleted.
+	Retention time.Duration
+
+	// Time between checks for retention enforcement.
+	RetentionCheckInterval time.Duration
+}
+
+// NewReplica returns a new instance of Replica.
+func NewReplica(db *litestream.DB, name string) *Replica {
+	return &Replica{
+		db:                     db,
+		name:                   name,
+		SyncInterval:           DefaultSyncInterval,
+		Retention:              DefaultRetention,
+		RetentionCheckInterval: DefaultRetentionCheckInterval,
+	}
+}
+
+// Name returns the name of the replica, if set.
+func (r *Replica) Name() string { return r.name }
+
+// DB returns the database the replica is attached to.
+func (r *Replica) DB() *litestream.DB { return r.db }
+
+// bucketClient lazily authenticates and returns the B2 bucket handle.
+func (r *Replica) bucketClient(ctx context.Context) (*b2.Bucket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.bucket != nil {
+		return r.bucket, nil
+	}
+
+	client, err := b2.NewClient(ctx, r.AccountID, r.ApplicationKey)
+	if err != nil {
+		return nil, fmt.Errorf("b2: cannot authenticate: %w", err)
+	}
+
+	bucket, err := client.Bucket(ctx, r.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("b2: cannot open bucket %q: %w", r.Bucket, err)
+	}
+
+	r.bucket = bucket
+	return r.bucket, nil
+}
+
+// objectKey returns the full object name for a given relative key, rooted under Path.
+func (r *Replica) objectKey(key string) string {
+	if r.Path == "" {
+		return key
+	}
+	return path.Join(r.Path, key)
+}
+
+// WriteSnapshot uploads a snapshot for generation at index to B2.
+func (r *Replica) WriteSnapshot(ctx context.Context, generation string, index int, rd io.Reader) error {
+	bucket, err := r.bucketClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := r.objectKey(path.Join("generations", generation, "snapshots", fmt.Sprintf("%08x.snapshot.lz4", index)))
+	w := bucket.Object(key).NewWriter(ctx)
+	if _, err := io.Copy(w, rd); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("b2: cannot write snapshot: %w", err)
+	}
+	return w.Close()
+}
+
+// WriteWALSegment uploads a single WAL segment to B2.
+func (r *Replica) WriteWALSegment(ctx context.Context, generation string, index int, offset int64, rd io.Reader) error {
+	bucket, err := r.bucketClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := r.objectKey(path.Join("generations", generation, "wal", fmt.Sprintf("%08x_%08x.wal.lz4", index, offset)))
+	w := bucket.Object(key).NewWriter(ctx)
+	if _, err := io.Copy(w, rd); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("b2: cannot write wal segment: %w", err)
+	}
+	return w.Close()
+}
+
+// Generations returns a list of available generation names.
+func (r *Replica) Generations(ctx context.Context) ([]string, error) {
+	bucket, err := r.bucketClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := r.objectKey("generations") + "/"
+	seen := make(map[string]struct{})
+	var generations []string
+
+	iter := bucket.List(ctx, b2.ListPrefix(prefix))
+	for iter.Next() {
+		name := strings.TrimPrefix(iter.Object().Name(), prefix)
+		parts := strings.SplitN(name, "/", 2)
+		if parts[0] == "" {
+			continue
+		}
+		if _, ok := seen[parts[0]]; !ok {
+			seen[parts[0]] = struct{}{}
+			generations = append(generations, parts[0])
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("b2: cannot list generations: %w", err)
+	}
+	return generations, nil
+}
+
+// EnforceRetention removes generations whose newest object is older than Retention.
+func (r *Replica) EnforceRetention(ctx context.Context) error {
+	generations, err := r.Generations(ctx)
+	if err != nil {
+		return err
+	}
+
+	bucket, err := r.bucketClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-r.Retention)
+	for _, generation := range generations {
+		prefix := r.objectKey(path.Join("generations", generation)) + "/"
+
+		var newest time.Time
+		var objects []*b2.Object
+		iter := bucket.List(ctx, b2.ListPrefix(prefix))
+		for iter.Next() {
+			obj := iter.Object()
+			attrs, err := obj.Attrs(ctx)
+			if err != nil {
+				return fmt.Errorf("b2: cannot stat object: %w", err)
+			}
+			if attrs.UploadTimestamp.After(newest) {
+				newest = attrs.UploadTimestamp
+			}
+			objects = append(objects, obj)
+		}
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("b2: cannot enumerate generation %s: %w", generation, err)
+		}
+
+		if newest.Before(cutoff) {
+			for _, obj := range objects {
+				if err := obj.Delete(ctx); err != nil {
+					return fmt.Errorf("b2: cannot delete object: %w", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// WALReader returns a reader for a single WAL segment at the given generation, index, and offset.
+func (r *Replica) WALReader(ctx context.Context, generation string, index int, offset int64) (io.ReadCloser, error) {
+	bucket, err := r.bucketClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := r.objectKey(path.Join("generations", generation, "wal", fmt.Sprintf("%08x_%08x.wal.lz4", index, offset)))
+	return bucket.Object(key).NewReader(ctx), nil
+}
+
+// SnapshotReader returns a reader for the snapshot at the given generation and index.
+func (r *Replica) SnapshotReader(ctx context.Context, generation string, index int) (io.ReadCloser, error) {
+	bucket, err := r.bucketClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := r.objectKey(path.Join("generations", generation, "snapshots", fmt.Sprintf("%08x.snapshot.lz4", index)))
+	return bucket.Object(key).NewReader(ctx), nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/benbjohnson/litestream/vault"
+)
+
+func TestMergeConfig(t *testing.T) {
+	dst := &Config{
+		Addr: ":9090",
+		DBs: []*DBConfig{
+			{Path: "/data/a.db", Replicas: []*ReplicaConfig{{Type: "file", Path: "/backup/a"}}},
+		},
+	}
+	fragment := Config{
+		Addr: ":9091",
+		DBs: []*DBConfig{
+			{Path: "/data/a.db", Replicas: []*ReplicaConfig{{Type: "s3", Bucket: "extra"}}},
+			{Path: "/data/b.db", Replicas: []*ReplicaConfig{{Type: "file", Path: "/backup/b"}}},
+		},
+	}
+
+	mergeConfig(dst, fragment)
+
+	if dst.Addr != ":9091" {
+		t.Errorf("Addr = %q, want %q (fragment should override)", dst.Addr, ":9091")
+	}
+	if len(dst.DBs) != 2 {
+		t.Fatalf("got %d dbs, want 2", len(dst.DBs))
+	}
+	if got := len(dst.DBs[0].Replicas); got != 2 {
+		t.Fatalf("got %d replicas on existing db, want 2 (additive merge)", got)
+	}
+	if dst.DBs[1].Path != "/data/b.db" {
+		t.Errorf("DBs[1].Path = %q, want %q (new db appended)", dst.DBs[1].Path, "/data/b.db")
+	}
+}
+
+func TestMergeConfig_EmptyAddrDoesNotOverride(t *testing.T) {
+	dst := &Config{Addr: ":9090"}
+	mergeConfig(dst, Config{})
+
+	if dst.Addr != ":9090" {
+		t.Errorf("Addr = %q, want %q (unset fragment field should not override)", dst.Addr, ":9090")
+	}
+}
+
+func TestMergeConfig_Vault(t *testing.T) {
+	dst := &Config{}
+	fragVault := &vault.Config{Address: "https://vault.example.com"}
+	mergeConfig(dst, Config{Vault: fragVault})
+
+	if dst.Vault != fragVault {
+		t.Errorf("Vault = %v, want fragment's Vault to be merged in, not dropped", dst.Vault)
+	}
+}
+
+func TestMergeConfig_NilVaultDoesNotOverride(t *testing.T) {
+	dstVault := &vault.Config{Address: "https://vault.example.com"}
+	dst := &Config{Vault: dstVault}
+	mergeConfig(dst, Config{})
+
+	if dst.Vault != dstVault {
+		t.Errorf("Vault = %v, want unset fragment field to not override", dst.Vault)
+	}
+}
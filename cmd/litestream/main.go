@@ -11,11 +11,19 @@ import (
 	"os/user"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/azblob"
+	"github.com/benbjohnson/litestream/b2"
+	"github.com/benbjohnson/litestream/crypto"
+	"github.com/benbjohnson/litestream/gcs"
 	"github.com/benbjohnson/litestream/s3"
+	"github.com/benbjohnson/litestream/sftp"
+	"github.com/benbjohnson/litestream/vault"
+	"github.com/benbjohnson/litestream/webdav"
 	"gopkg.in/yaml.v2"
 )
 
@@ -61,6 +69,8 @@ func (m *Main) Run(ctx context.Context, args []string) (err error) {
 		return (&SnapshotsCommand{}).Run(ctx, args)
 	case "validate":
 		return (&ValidateCommand{}).Run(ctx, args)
+	case "validate-config":
+		return (&ValidateConfigCommand{}).Run(ctx, args)
 	case "version":
 		return (&VersionCommand{}).Run(ctx, args)
 	case "wal":
@@ -84,19 +94,25 @@ Usage:
 
 The commands are:
 
-	generations  list available generations for a database
-	replicate    runs a server to replicate databases
-	restore      recovers database backup from a replica
-	snapshots    list available snapshots for a database
-	validate     checks replica to ensure a consistent state with primary
-	version      prints the version
-	wal          list available WAL files for a database
+	generations      list available generations for a database
+	replicate        runs a server to replicate databases
+	restore          recovers database backup from a replica
+	snapshots        list available snapshots for a database
+	validate         checks replica to ensure a consistent state with primary
+	validate-config  checks the config file for errors and exits, without replicating
+	version          prints the version
+	wal              list available WAL files for a database
 `[1:])
 }
 
 // Default configuration settings.
 const (
 	DefaultAddr = ":9090"
+
+	// DefaultIncludeGlob is scanned for conf.d-style config fragments when
+	// Config.Include is unset, so packages/config-management can drop
+	// per-database snippets without editing the top-level config file.
+	DefaultIncludeGlob = "/etc/litestream.d/*.yml"
 )
 
 // Config represents a configuration file for the litestream daemon.
@@ -106,14 +122,32 @@ type Config struct {
 
 	// List of databases to manage.
 	DBs []*DBConfig `yaml:"dbs"`
+
+	// Additional YAML fragments to load and merge into this config. Entries
+	// may be glob patterns. Defaults to DefaultIncludeGlob if unset.
+	Include []string `yaml:"include"`
+
+	// Vault server used to resolve "vault://" credential URIs in replica configs.
+	Vault *vault.Config `yaml:"vault"`
 }
 
+// Normalize normalizes every DB, collecting errors from all of them rather
+// than stopping at the first so that ReadConfigFile's later validation pass
+// sees every DB's normalized fields, not just those before the first failure.
 func (c *Config) Normalize() error {
+	var errs ConfigErrors
 	for i := range c.DBs {
 		if err := c.DBs[i].Normalize(); err != nil {
-			return err
+			if dbErrs, ok := err.(ConfigErrors); ok {
+				errs = append(errs, dbErrs...)
+			} else {
+				errs = append(errs, &ConfigError{Path: c.DBs[i].Path, Err: err})
+			}
 		}
 	}
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
@@ -134,51 +168,334 @@ func (c *Config) DBConfig(path string) *DBConfig {
 }
 
 // ReadConfigFile unmarshals config from filename. Expands path if needed.
+// Fragments referenced by an "include:" directive (or, if unset,
+// DefaultIncludeGlob) are loaded and merged in afterward.
+//
+// Parsing is strict (unknown keys are rejected) and every problem found
+// across parsing, normalization, and validation is collected and returned
+// together as a ConfigErrors, rather than stopping at the first one. A YAML
+// error other than a strict-mode *yaml.TypeError (e.g. a syntax error) is
+// returned on its own.
 func ReadConfigFile(filename string) (Config, error) {
 	config := DefaultConfig()
 
-	// Expand filename, if necessary.
-	if prefix := "~" + string(os.PathSeparator); strings.HasPrefix(filename, prefix) {
-		u, err := user.Current()
-		if err != nil {
-			return config, err
-		} else if u.HomeDir == "" {
-			return config, fmt.Errorf("home directory unset")
-		}
-		filename = filepath.Join(u.HomeDir, strings.TrimPrefix(filename, prefix))
+	filename, err := expandPath(filename)
+	if err != nil {
+		return config, err
 	}
 
 	// Read & deserialize configuration.
-	if buf, err := ioutil.ReadFile(filename); os.IsNotExist(err) {
+	var errs ConfigErrors
+	buf, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
 		return config, fmt.Errorf("config file not found: %s", filename)
 	} else if err != nil {
 		return config, err
-	} else if err := yaml.Unmarshal(buf, &config); err != nil {
-		return config, err
+	} else if err := yaml.UnmarshalStrict(buf, &config); err != nil {
+		// A *yaml.TypeError still leaves the known fields decoded, so its
+		// problems are collected and checking continues.
+		if _, ok := err.(*yaml.TypeError); !ok {
+			return config, unmarshalConfigErrors(filename, err)
+		}
+		errs = append(errs, unmarshalConfigErrors(filename, err)...)
+	}
+
+	errs = append(errs, validateUniqueDBPaths(filename, config.DBs)...)
+
+	includes := config.Include
+	if len(includes) == 0 {
+		includes = []string{DefaultIncludeGlob}
+	}
+
+	for _, pattern := range includes {
+		pattern, err := expandPath(pattern)
+		if err != nil {
+			return config, err
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return config, fmt.Errorf("cannot expand include pattern %q: %w", pattern, err)
+		}
+
+		for _, match := range matches {
+			if match == filename {
+				continue // don't re-include the top-level config file itself
+			}
+
+			var fragment Config
+			if buf, err := ioutil.ReadFile(match); err != nil {
+				return config, fmt.Errorf("cannot read included config %q: %w", match, err)
+			} else if err := yaml.UnmarshalStrict(buf, &fragment); err != nil {
+				if _, ok := err.(*yaml.TypeError); !ok {
+					return config, unmarshalConfigErrors(match, err)
+				}
+				errs = append(errs, unmarshalConfigErrors(match, err)...)
+			}
+
+			errs = append(errs, validateUniqueDBPaths(match, fragment.DBs)...)
+			mergeConfig(&config, fragment)
+		}
 	}
 
 	if err := config.Normalize(); err != nil {
-		return config, err
+		if normErrs, ok := err.(ConfigErrors); ok {
+			for _, e := range normErrs {
+				e.File = filename
+			}
+			errs = append(errs, normErrs...)
+		} else {
+			errs = append(errs, &ConfigError{File: filename, Err: err})
+		}
+	}
+	errs = append(errs, validateConfig(filename, &config)...)
+
+	if len(errs) > 0 {
+		return config, errs
 	}
 	return config, nil
 }
 
+// ConfigError is a single configuration problem, scoped to the file it came
+// from and, where applicable, the db path and field within it.
+type ConfigError struct {
+	File  string
+	Path  string // db path, if the error is scoped to one db
+	Field string // dotted field name, if the error is scoped to one field
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	switch {
+	case e.Path != "" && e.Field != "":
+		return fmt.Sprintf("%s: %s: %s: %s", e.File, e.Path, e.Field, e.Err)
+	case e.Path != "":
+		return fmt.Sprintf("%s: %s: %s", e.File, e.Path, e.Err)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s", e.File, e.Err)
+	default:
+		return e.Err.Error()
+	}
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// ConfigErrors is a non-empty list of ConfigError. ReadConfigFile returns one
+// instead of a plain error so that every problem in a config (unknown keys,
+// missing fields, duplicate paths, ...) is reported in a single run rather
+// than one-by-one across repeated fix-and-rerun cycles.
+type ConfigErrors []*ConfigError
+
+func (errs ConfigErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("%d config error(s):\n%s", len(errs), strings.Join(lines, "\n"))
+}
+
+// unmarshalConfigErrors converts a yaml.UnmarshalStrict error into one
+// ConfigError per underlying problem. gopkg.in/yaml.v2 aggregates strict-mode
+// failures (e.g. several unknown keys) into a single *yaml.TypeError, so that
+// case is unpacked; anything else is wrapped as a single ConfigError.
+func unmarshalConfigErrors(file string, err error) ConfigErrors {
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		errs := make(ConfigErrors, len(typeErr.Errors))
+		for i, msg := range typeErr.Errors {
+			errs[i] = &ConfigError{File: file, Err: fmt.Errorf("%s", msg)}
+		}
+		return errs
+	}
+	return ConfigErrors{{File: file, Err: err}}
+}
+
+// expandPath expands a leading "~/" in filename to the current user's home directory.
+func expandPath(filename string) (string, error) {
+	prefix := "~" + string(os.PathSeparator)
+	if !strings.HasPrefix(filename, prefix) {
+		return filename, nil
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	} else if u.HomeDir == "" {
+		return "", fmt.Errorf("home directory unset")
+	}
+	return filepath.Join(u.HomeDir, strings.TrimPrefix(filename, prefix)), nil
+}
+
+// validateUniqueDBPaths returns one ConfigError per path that appears more
+// than once within dbs (a single file's db list), which would otherwise
+// silently shadow one another.
+func validateUniqueDBPaths(file string, dbs []*DBConfig) ConfigErrors {
+	var errs ConfigErrors
+	seen := make(map[string]struct{}, len(dbs))
+	for _, db := range dbs {
+		if _, ok := seen[db.Path]; ok {
+			errs = append(errs, &ConfigError{File: file, Path: db.Path, Err: fmt.Errorf("duplicate db path")})
+			continue
+		}
+		seen[db.Path] = struct{}{}
+	}
+	return errs
+}
+
+// mergeConfig merges an included fragment into dst. DBs are matched by path:
+// a db already present in dst has the fragment's replicas appended to it
+// (additive), while new db paths are appended as whole new entries. Scalar
+// top-level fields (Addr, Vault) in the fragment override dst's when set.
+func mergeConfig(dst *Config, fragment Config) {
+	if fragment.Addr != "" {
+		dst.Addr = fragment.Addr
+	}
+	if fragment.Vault != nil {
+		dst.Vault = fragment.Vault
+	}
+
+	for _, fragDB := range fragment.DBs {
+		if dstDB := dst.DBConfig(fragDB.Path); dstDB != nil {
+			dstDB.Replicas = append(dstDB.Replicas, fragDB.Replicas...)
+			continue
+		}
+		dst.DBs = append(dst.DBs, fragDB)
+	}
+}
+
+// validateConfig runs structural checks against the fully merged and
+// normalized config that unmarshaling alone can't catch: missing db paths
+// and, per replica, an unknown type or missing required fields. Unresolvable
+// "~" paths are caught earlier, by DBConfig.Normalize. It collects every
+// problem it finds instead of stopping at the first.
+func validateConfig(file string, config *Config) ConfigErrors {
+	var errs ConfigErrors
+
+	for _, db := range config.DBs {
+		if db.Path == "" {
+			errs = append(errs, &ConfigError{File: file, Err: fmt.Errorf("db path required")})
+			continue
+		}
+
+		for _, rc := range db.Replicas {
+			errs = append(errs, validateReplica(file, db.Path, rc)...)
+		}
+	}
+	return errs
+}
+
+// validateReplica checks a single, already-normalized replica config for an
+// unknown type or missing fields required by that type, mirroring the checks
+// newReplicaFromConfig's backend constructors perform one-at-a-time, so that
+// all of them surface together instead of being discovered one fix at a time.
+func validateReplica(file, dbPath string, rc *ReplicaConfig) ConfigErrors {
+	var errs ConfigErrors
+	field := func(name string, err error) {
+		errs = append(errs, &ConfigError{File: file, Path: dbPath, Field: name, Err: err})
+	}
+
+	typ := rc.Type
+	if typ == "" {
+		typ = "file"
+	}
+	if _, ok := replicaFactories[typ]; !ok {
+		field("type", fmt.Errorf("unknown replica type: %q", rc.Type))
+		return errs
+	}
+
+	switch typ {
+	case "file":
+		if rc.Path == "" {
+			field("path", fmt.Errorf("file replica path required"))
+		}
+	case "s3":
+		if rc.Region == "" {
+			field("region", fmt.Errorf("s3 region required"))
+		}
+		if rc.Bucket == "" {
+			field("bucket", fmt.Errorf("s3 bucket required"))
+		}
+	case "gcs":
+		if rc.Bucket == "" {
+			field("bucket", fmt.Errorf("gcs bucket required"))
+		}
+	case "azblob":
+		if rc.AccountName == "" {
+			field("account-name", fmt.Errorf("azblob account name required"))
+		}
+		if rc.AccountKey == "" {
+			field("account-key", fmt.Errorf("azblob account key required"))
+		}
+		if rc.Container == "" {
+			field("container", fmt.Errorf("azblob container required"))
+		}
+	case "sftp":
+		if rc.Host == "" {
+			field("host", fmt.Errorf("sftp host required"))
+		}
+		if rc.User == "" {
+			field("user", fmt.Errorf("sftp user required"))
+		}
+		if rc.Path == "" {
+			field("path", fmt.Errorf("sftp path required"))
+		}
+		if rc.KnownHostsPath == "" {
+			field("known-hosts-path", fmt.Errorf("sftp known-hosts-path required to verify the remote host key"))
+		}
+	case "webdav":
+		if rc.URL == "" {
+			field("url", fmt.Errorf("webdav url required"))
+		}
+	case "b2":
+		if rc.AccountID == "" {
+			field("account-id", fmt.Errorf("b2 account id required"))
+		}
+		if rc.ApplicationKey == "" {
+			field("application-key", fmt.Errorf("b2 application key required"))
+		}
+		if rc.Bucket == "" {
+			field("bucket", fmt.Errorf("b2 bucket required"))
+		}
+	}
+
+	if rc.Encryption != nil {
+		enc := &crypto.Replica{Type: rc.Encryption.Type, Recipients: rc.Encryption.Recipients, KeyFile: rc.Encryption.KeyFile}
+		if err := enc.Validate(); err != nil {
+			field("encryption", err)
+		}
+	}
+
+	return errs
+}
+
 type DBConfig struct {
 	Path     string           `yaml:"path"`
 	Replicas []*ReplicaConfig `yaml:"replicas"`
 }
 
+// Normalize expands a leading "~" in Path and normalizes every replica,
+// collecting errors from all of them rather than stopping at the first.
 func (c *DBConfig) Normalize() error {
+	var errs ConfigErrors
+
+	if expanded, err := expandPath(c.Path); err != nil {
+		errs = append(errs, &ConfigError{Path: c.Path, Field: "path", Err: err})
+	} else {
+		c.Path = expanded
+	}
+
 	for i := range c.Replicas {
 		if err := c.Replicas[i].Normalize(); err != nil {
-			return err
+			errs = append(errs, &ConfigError{Path: c.Path, Err: err})
 		}
 	}
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
 type ReplicaConfig struct {
-	Type                   string        `yaml:"type"` // "file", "s3"
+	Type                   string        `yaml:"type"` // "file", "s3", "gcs", "azblob", "sftp", "webdav", "b2"
 	Name                   string        `yaml:"name"` // name of replica, optional.
 	Path                   string        `yaml:"path"`
 	Retention              time.Duration `yaml:"retention"`
@@ -186,10 +503,55 @@ type ReplicaConfig struct {
 	SyncInterval           time.Duration `yaml:"sync-interval"` // s3 only
 
 	// S3 settings
+	//
+	// AccessKeyID, SecretAccessKey, and SessionToken may each be a literal
+	// value or a "vault://<path>#<field>" URI resolved against Config.Vault.
+	// If left blank after vault resolution, they fall back to the standard
+	// AWS_* environment variables and finally to the AWS SDK's own default
+	// credential chain (EC2/ECS/EKS instance role).
 	AccessKeyID     string `yaml:"access-key-id"`
 	SecretAccessKey string `yaml:"secret-access-key"`
+	SessionToken    string `yaml:"session-token"`
 	Region          string `yaml:"region"`
 	Bucket          string `yaml:"bucket"`
+	Endpoint        string `yaml:"endpoint"`         // custom endpoint for S3-compatible stores (MinIO, Wasabi, Ceph, ...)
+	ForcePathStyle  bool   `yaml:"force-path-style"` // use bucket.path addressing instead of virtual-hosted style
+	SkipVerify      bool   `yaml:"skip-verify"`      // skip TLS certificate verification, for self-signed endpoints
+	Prefix          string `yaml:"prefix"`           // deployment-wide key prefix, applied ahead of Path within Bucket
+
+	// Azure Blob Storage settings
+	AccountName string `yaml:"account-name"`
+	AccountKey  string `yaml:"account-key"`
+	Container   string `yaml:"container"`
+
+	// SFTP settings
+	Host           string `yaml:"host"`
+	User           string `yaml:"user"`
+	Password       string `yaml:"password"`
+	KeyPath        string `yaml:"key-path"`
+	KnownHostsPath string `yaml:"known-hosts-path"` // required; verifies the remote host key
+
+	// WebDAV settings
+	URL string `yaml:"url"`
+
+	// Backblaze B2 settings
+	AccountID      string `yaml:"account-id"`
+	ApplicationKey string `yaml:"application-key"`
+
+	// Client-side envelope encryption, applied uniformly on top of whichever
+	// backend Type selects.
+	Encryption *EncryptionConfig `yaml:"encryption"`
+
+	// Extended backend-specific options (e.g. "s3.endpoint", "file.fsync"),
+	// merged with any CLI-provided -o flags, with the CLI taking precedence.
+	Options map[string]string `yaml:"options"`
+}
+
+// EncryptionConfig configures client-side envelope encryption for a replica.
+type EncryptionConfig struct {
+	Type       string   `yaml:"type"` // "age" or "aes256-gcm"
+	Recipients []string `yaml:"recipients"`
+	KeyFile    string   `yaml:"key-file"`
 }
 
 func (c *ReplicaConfig) Normalize() error {
@@ -220,14 +582,92 @@ func (c *ReplicaConfig) Normalize() error {
 	case "s3":
 		c.Type = u.Scheme
 		c.Path = strings.TrimPrefix(path.Clean(u.Path), "/")
-		c.Bucket = u.Host
 		if u := u.User; u != nil {
 			c.AccessKeyID = u.Username()
 			c.SecretAccessKey, _ = u.Password()
 		}
+
+		// Only an explicit "endpoint" query param selects a self-hosted
+		// S3-compatible store (MinIO, Wasabi, Ceph, DigitalOcean Spaces); the
+		// host is then taken to be just the bucket name. Without it, the full
+		// host is the bucket name as-is, dots and all: AWS bucket names are
+		// allowed to contain dots (e.g. "s3://backups.example.com/path" is a
+		// bucket literally named "backups.example.com"), so guessing an
+		// endpoint from a bare dot would silently misinterpret valid buckets.
+		q := u.Query()
+		var bucket string
+		if v := q.Get("endpoint"); v != "" {
+			bucket, c.Endpoint = u.Host, v
+		} else {
+			bucket = u.Host
+		}
+		if c.Bucket != "" && c.Bucket != bucket {
+			return fmt.Errorf("conflicting s3 bucket: %q in bucket field, %q in path", c.Bucket, bucket)
+		}
+		c.Bucket = bucket
+
+		if v := q.Get("force-path-style"); v != "" {
+			c.ForcePathStyle, _ = strconv.ParseBool(v)
+		}
+		return nil
+
+	case "gs":
+		c.Type = "gcs"
+		c.Path = strings.TrimPrefix(path.Clean(u.Path), "/")
+		c.Bucket = u.Host
+		return nil
+
+	case "azblob":
+		c.Type = u.Scheme
+		c.Path = strings.TrimPrefix(path.Clean(u.Path), "/")
+		c.Container = u.Host
+		if u := u.User; u != nil {
+			c.AccountName = u.Username()
+			c.AccountKey, _ = u.Password()
+		}
+		return nil
+
+	case "sftp":
+		c.Type = u.Scheme
+		c.Path = u.Path
+		c.Host = u.Host
+		if u := u.User; u != nil {
+			c.User = u.Username()
+			c.Password, _ = u.Password()
+		}
+		return nil
+
+	case "webdav", "webdavs":
+		c.Type = "webdav"
+		c.Path = strings.TrimPrefix(path.Clean(u.Path), "/")
+		if u.User != nil {
+			c.User = u.User.Username()
+			c.Password, _ = u.User.Password()
+		}
+		// URL is the HTTP(S) server root; the remote directory is kept in Path.
+		scheme := "http"
+		if u.Scheme == "webdavs" {
+			scheme = "https"
+		}
+		c.URL = (&url.URL{Scheme: scheme, Host: u.Host}).String()
+		return nil
+
+	case "b2":
+		c.Type = u.Scheme
+		c.Path = strings.TrimPrefix(path.Clean(u.Path), "/")
+		c.Bucket = u.Host
+		if u := u.User; u != nil {
+			c.AccountID = u.Username()
+			c.ApplicationKey, _ = u.Password()
+		}
 		return nil
 
 	default:
+		if _, ok := replicaFactories[u.Scheme]; ok {
+			c.Type = u.Scheme
+			c.Path = strings.TrimPrefix(path.Clean(u.Path), "/")
+			return nil
+		}
 		return fmt.Errorf("unrecognized replica type in path scheme: %s", c.Path)
 	}
 }
@@ -244,14 +684,85 @@ func registerConfigFlag(fs *flag.FlagSet, p *string) {
 	fs.StringVar(p, "config", DefaultConfigPath(), "config path")
 }
 
-// newDBFromConfig instantiates a DB based on a configuration.
-func newDBFromConfig(config *DBConfig) (*litestream.DB, error) {
+// optionsFlag implements flag.Value and collects repeated "-o key=value"
+// flags into a map, mirroring restic's --option.
+type optionsFlag map[string]string
+
+func (f optionsFlag) String() string {
+	return fmt.Sprintf("%v", map[string]string(f))
+}
+
+func (f optionsFlag) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i == -1 {
+		return fmt.Errorf("invalid option %q, must be in key=value form", s)
+	}
+	f[s[:i]] = s[i+1:]
+	return nil
+}
+
+// registerReplicaOptionsFlag registers a repeatable "-o" flag that accumulates
+// extended replica options (e.g. "-o s3.endpoint=...") into *p.
+func registerReplicaOptionsFlag(fs *flag.FlagSet, p *map[string]string) {
+	if *p == nil {
+		*p = make(map[string]string)
+	}
+	fs.Var(optionsFlag(*p), "o", "extended replica option in key=value form, may be repeated")
+}
+
+// resolveCredential returns raw unchanged unless it is a "vault://" URI, in
+// which case it is resolved against vaultClient. Returns an error if raw is
+// a vault URI but no vault configuration is available.
+func resolveCredential(vaultClient *vault.Client, raw string) (string, error) {
+	if !strings.HasPrefix(raw, "vault://") {
+		return raw, nil
+	}
+	if vaultClient == nil {
+		return "", fmt.Errorf("cannot resolve %q: no vault configuration", raw)
+	}
+	return vaultClient.Resolve(raw)
+}
+
+// mergeOptions combines extended per-replica options from the YAML config
+// with CLI-provided options, with the CLI options taking precedence.
+func mergeOptions(yamlOptions, cliOptions map[string]string) map[string]string {
+	merged := make(map[string]string, len(yamlOptions)+len(cliOptions))
+	for k, v := range yamlOptions {
+		merged[k] = v
+	}
+	for k, v := range cliOptions {
+		merged[k] = v
+	}
+	return merged
+}
+
+// scopedOptions returns the options under the given backend prefix (e.g.
+// "s3"), with the "<prefix>." key prefix stripped (e.g. "s3.endpoint" -> "endpoint").
+func scopedOptions(options map[string]string, prefix string) map[string]string {
+	scoped := make(map[string]string)
+	for k, v := range options {
+		if rest := strings.TrimPrefix(k, prefix+"."); rest != k {
+			scoped[rest] = v
+		}
+	}
+	return scoped
+}
+
+// newDBFromConfig instantiates a DB based on a configuration. cliOptions holds
+// any "-o key=value" flags passed on the command line, which take precedence
+// over the matching replica's "options:" map in the YAML config.
+func newDBFromConfig(config *DBConfig, cliOptions map[string]string, vaultConfig *vault.Config) (*litestream.DB, error) {
 	// Initialize database with given path.
 	db := litestream.NewDB(config.Path)
 
+	var vaultClient *vault.Client
+	if vaultConfig != nil {
+		vaultClient = vault.NewClient(*vaultConfig)
+	}
+
 	// Instantiate and attach replicas.
 	for _, rconfig := range config.Replicas {
-		r, err := newReplicaFromConfig(db, rconfig)
+		r, err := newReplicaFromConfig(db, rconfig, mergeOptions(rconfig.Options, cliOptions), vaultClient)
 		if err != nil {
 			return nil, err
 		}
@@ -261,20 +772,121 @@ func newDBFromConfig(config *DBConfig) (*litestream.DB, error) {
 	return db, nil
 }
 
-// newReplicaFromConfig instantiates a replica for a DB based on a config.
-func newReplicaFromConfig(db *litestream.DB, config *ReplicaConfig) (litestream.Replica, error) {
-	switch config.Type {
-	case "", "file":
-		return newFileReplicaFromConfig(db, config)
-	case "s3":
-		return newS3ReplicaFromConfig(db, config)
-	default:
+// DBsFromConfig builds every DB declared in config. Each DB's replicas are
+// wired to config.Vault for resolving "vault://" credentials and to
+// cliOptions (e.g. accumulated from repeated "-o key=value" flags via
+// registerReplicaOptionsFlag) for options that should override the YAML
+// config. Command entry points that load a Config (replicate, restore, ...)
+// should build their DBs through this function rather than calling
+// newDBFromConfig per DB themselves, so Vault and CLI options are always
+// applied the same way.
+func DBsFromConfig(config *Config, cliOptions map[string]string) ([]*litestream.DB, error) {
+	dbs := make([]*litestream.DB, 0, len(config.DBs))
+	for _, dbConfig := range config.DBs {
+		db, err := newDBFromConfig(dbConfig, cliOptions, config.Vault)
+		if err != nil {
+			return nil, err
+		}
+		dbs = append(dbs, db)
+	}
+	return dbs, nil
+}
+
+// ReplicaFactory instantiates a replica for a DB based on a config and its
+// merged extended options. Backends register a factory under their type
+// name via RegisterReplica so that newReplicaFromConfig can dispatch
+// without a hardcoded switch statement.
+type ReplicaFactory func(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (litestream.Replica, error)
+
+// replicaFactories holds the set of registered replica backends, keyed by
+// ReplicaConfig.Type (e.g. "s3"). This is also the replica URL scheme, except
+// that gs:// URLs map to "gcs" and webdavs:// URLs to "webdav".
+var replicaFactories = make(map[string]ReplicaFactory)
+
+// RegisterReplica registers a replica factory under scheme. It panics if
+// scheme is empty or already registered, matching the fail-fast behavior of
+// other global registries (e.g. database/sql drivers).
+func RegisterReplica(scheme string, factory ReplicaFactory) {
+	if scheme == "" {
+		panic("litestream: RegisterReplica: scheme required")
+	} else if factory == nil {
+		panic("litestream: RegisterReplica: factory required")
+	} else if _, ok := replicaFactories[scheme]; ok {
+		panic(fmt.Sprintf("litestream: replica backend already registered: %s", scheme))
+	}
+	replicaFactories[scheme] = factory
+}
+
+func init() {
+	RegisterReplica("file", withEncryption(func(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (litestream.Replica, error) {
+		return newFileReplicaFromConfig(db, config, options, vaultClient)
+	}))
+	RegisterReplica("s3", withEncryption(func(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (litestream.Replica, error) {
+		return newS3ReplicaFromConfig(db, config, options, vaultClient)
+	}))
+	RegisterReplica("gcs", withEncryption(func(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (litestream.Replica, error) {
+		return newGCSReplicaFromConfig(db, config, options, vaultClient)
+	}))
+	RegisterReplica("azblob", withEncryption(func(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (litestream.Replica, error) {
+		return newAzblobReplicaFromConfig(db, config, options, vaultClient)
+	}))
+	RegisterReplica("sftp", withEncryption(func(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (litestream.Replica, error) {
+		return newSFTPReplicaFromConfig(db, config, options, vaultClient)
+	}))
+	RegisterReplica("webdav", withEncryption(func(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (litestream.Replica, error) {
+		return newWebDAVReplicaFromConfig(db, config, options, vaultClient)
+	}))
+	RegisterReplica("b2", withEncryption(func(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (litestream.Replica, error) {
+		return newB2ReplicaFromConfig(db, config, options, vaultClient)
+	}))
+}
+
+// withEncryption wraps factory so that, when a replica config carries an
+// Encryption block, the resulting replica is decorated to transparently
+// encrypt/decrypt snapshots and WAL segments. This runs after the underlying
+// backend is built, so it applies uniformly to every registered scheme.
+func withEncryption(factory ReplicaFactory) ReplicaFactory {
+	return func(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (litestream.Replica, error) {
+		r, err := factory(db, config, options, vaultClient)
+		if err != nil || config.Encryption == nil {
+			return r, err
+		}
+
+		backend, ok := r.(crypto.Backend)
+		if !ok {
+			return nil, fmt.Errorf("%s: replica backend does not support encryption", db.Path())
+		}
+
+		enc := &crypto.Replica{
+			Backend:    backend,
+			Type:       config.Encryption.Type,
+			Recipients: config.Encryption.Recipients,
+			KeyFile:    config.Encryption.KeyFile,
+		}
+		if err := enc.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", db.Path(), err)
+		}
+		return enc, nil
+	}
+}
+
+// newReplicaFromConfig instantiates a replica for a DB based on a config and
+// its merged extended options (see mergeOptions).
+func newReplicaFromConfig(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (litestream.Replica, error) {
+	typ := config.Type
+	if typ == "" {
+		typ = "file"
+	}
+
+	factory, ok := replicaFactories[typ]
+	if !ok {
 		return nil, fmt.Errorf("unknown replica type in config: %q", config.Type)
 	}
+	return factory(db, config, options, vaultClient)
 }
 
 // newFileReplicaFromConfig returns a new instance of FileReplica build from config.
-func newFileReplicaFromConfig(db *litestream.DB, config *ReplicaConfig) (*litestream.FileReplica, error) {
+func newFileReplicaFromConfig(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (*litestream.FileReplica, error) {
 	if config.Path == "" {
 		return nil, fmt.Errorf("%s: file replica path required", db.Path())
 	}
@@ -286,27 +898,102 @@ func newFileReplicaFromConfig(db *litestream.DB, config *ReplicaConfig) (*litest
 	if v := config.RetentionCheckInterval; v > 0 {
 		r.RetentionCheckInterval = v
 	}
+
+	fileOptions := scopedOptions(options, "file")
+	if v := fileOptions["fsync"]; v != "" {
+		fsync, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid file.fsync option: %w", db.Path(), err)
+		}
+		r.Fsync = fsync
+	}
 	return r, nil
 }
 
 // newS3ReplicaFromConfig returns a new instance of S3Replica build from config.
-func newS3ReplicaFromConfig(db *litestream.DB, config *ReplicaConfig) (*s3.Replica, error) {
-	if config.AccessKeyID == "" {
-		return nil, fmt.Errorf("%s: s3 access key id required", db.Path())
-	} else if config.SecretAccessKey == "" {
-		return nil, fmt.Errorf("%s: s3 secret access key required", db.Path())
-	} else if config.Region == "" {
+func newS3ReplicaFromConfig(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (*s3.Replica, error) {
+	if config.Region == "" {
 		return nil, fmt.Errorf("%s: s3 region required", db.Path())
 	} else if config.Bucket == "" {
 		return nil, fmt.Errorf("%s: s3 bucket required", db.Path())
 	}
 
+	// Resolve credential-bearing fields through the chain: explicit config
+	// value (a literal or a "vault://" URI) -> AWS_* environment variables ->
+	// left blank, deferring to the AWS SDK's own default credential chain
+	// (shared config file, EC2/ECS/EKS instance role).
+	accessKeyID, err := resolveCredential(vaultClient, config.AccessKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", db.Path(), err)
+	}
+	secretAccessKey, err := resolveCredential(vaultClient, config.SecretAccessKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", db.Path(), err)
+	}
+	sessionToken, err := resolveCredential(vaultClient, config.SessionToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", db.Path(), err)
+	}
+	if accessKeyID == "" {
+		accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if secretAccessKey == "" {
+		secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if sessionToken == "" {
+		sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+
 	r := s3.NewReplica(db, config.Name)
-	r.AccessKeyID = config.AccessKeyID
-	r.SecretAccessKey = config.SecretAccessKey
+	r.AccessKeyID = accessKeyID
+	r.SecretAccessKey = secretAccessKey
+	r.SessionToken = sessionToken
 	r.Region = config.Region
 	r.Bucket = config.Bucket
 	r.Path = config.Path
+	if config.Prefix != "" {
+		r.Path = path.Join(config.Prefix, config.Path)
+	}
+	r.Endpoint = config.Endpoint
+	r.ForcePathStyle = config.ForcePathStyle
+	r.SkipVerify = config.SkipVerify
+
+	if v := config.Retention; v > 0 {
+		r.Retention = v
+	}
+	if v := config.RetentionCheckInterval; v > 0 {
+		r.RetentionCheckInterval = v
+	}
+	if v := config.SyncInterval; v > 0 {
+		r.SyncInterval = v
+	}
+
+	s3Options := scopedOptions(options, "s3")
+	if v := s3Options["endpoint"]; v != "" {
+		r.Endpoint = v
+	}
+	if v := s3Options["force-path-style"]; v != "" {
+		forcePathStyle, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid s3.force-path-style option: %w", db.Path(), err)
+		}
+		r.ForcePathStyle = forcePathStyle
+	}
+	if v := s3Options["sse"]; v != "" {
+		r.SSE = v
+	}
+	return r, nil
+}
+
+// newGCSReplicaFromConfig returns a new instance of gcs.Replica build from config.
+func newGCSReplicaFromConfig(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (*gcs.Replica, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("%s: gcs bucket required", db.Path())
+	}
+
+	r := gcs.NewReplica(db, config.Name)
+	r.Bucket = config.Bucket
+	r.Path = config.Path
 
 	if v := config.Retention; v > 0 {
 		r.Retention = v
@@ -319,3 +1006,149 @@ func newS3ReplicaFromConfig(db *litestream.DB, config *ReplicaConfig) (*s3.Repli
 	}
 	return r, nil
 }
+
+// newAzblobReplicaFromConfig returns a new instance of azblob.Replica build from config.
+func newAzblobReplicaFromConfig(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (*azblob.Replica, error) {
+	if config.AccountName == "" {
+		return nil, fmt.Errorf("%s: azblob account name required", db.Path())
+	} else if config.AccountKey == "" {
+		return nil, fmt.Errorf("%s: azblob account key required", db.Path())
+	} else if config.Container == "" {
+		return nil, fmt.Errorf("%s: azblob container required", db.Path())
+	}
+
+	r := azblob.NewReplica(db, config.Name)
+	r.AccountName = config.AccountName
+	r.AccountKey = config.AccountKey
+	r.Container = config.Container
+	r.Path = config.Path
+
+	if v := config.Retention; v > 0 {
+		r.Retention = v
+	}
+	if v := config.RetentionCheckInterval; v > 0 {
+		r.RetentionCheckInterval = v
+	}
+	if v := config.SyncInterval; v > 0 {
+		r.SyncInterval = v
+	}
+	return r, nil
+}
+
+// newSFTPReplicaFromConfig returns a new instance of sftp.Replica build from config.
+func newSFTPReplicaFromConfig(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (*sftp.Replica, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("%s: sftp host required", db.Path())
+	} else if config.User == "" {
+		return nil, fmt.Errorf("%s: sftp user required", db.Path())
+	} else if config.Path == "" {
+		return nil, fmt.Errorf("%s: sftp path required", db.Path())
+	} else if config.KnownHostsPath == "" {
+		return nil, fmt.Errorf("%s: sftp known-hosts-path required to verify the remote host key", db.Path())
+	}
+
+	r := sftp.NewReplica(db, config.Name)
+	r.Host = config.Host
+	r.User = config.User
+	r.Password = config.Password
+	r.KeyPath = config.KeyPath
+	r.KnownHostsPath = config.KnownHostsPath
+	r.Path = config.Path
+
+	if v := config.Retention; v > 0 {
+		r.Retention = v
+	}
+	if v := config.RetentionCheckInterval; v > 0 {
+		r.RetentionCheckInterval = v
+	}
+	if v := config.SyncInterval; v > 0 {
+		r.SyncInterval = v
+	}
+	return r, nil
+}
+
+// newWebDAVReplicaFromConfig returns a new instance of webdav.Replica build from config.
+func newWebDAVReplicaFromConfig(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (*webdav.Replica, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("%s: webdav url required", db.Path())
+	}
+
+	r := webdav.NewReplica(db, config.Name)
+	r.URL = config.URL
+	r.User = config.User
+	r.Password = config.Password
+	r.Path = config.Path
+
+	if v := config.Retention; v > 0 {
+		r.Retention = v
+	}
+	if v := config.RetentionCheckInterval; v > 0 {
+		r.RetentionCheckInterval = v
+	}
+	if v := config.SyncInterval; v > 0 {
+		r.SyncInterval = v
+	}
+	return r, nil
+}
+
+func newB2ReplicaFromConfig(db *litestream.DB, config *ReplicaConfig, options map[string]string, vaultClient *vault.Client) (*b2.Replica, error) {
+	if config.AccountID == "" {
+		return nil, fmt.Errorf("%s: b2 account id required", db.Path())
+	} else if config.ApplicationKey == "" {
+		return nil, fmt.Errorf("%s: b2 application key required", db.Path())
+	} else if config.Bucket == "" {
+		return nil, fmt.Errorf("%s: b2 bucket required", db.Path())
+	}
+
+	r := b2.NewReplica(db, config.Name)
+	r.AccountID = config.AccountID
+	r.ApplicationKey = config.ApplicationKey
+	r.Bucket = config.Bucket
+	r.Path = config.Path
+
+	if v := config.Retention; v > 0 {
+		r.Retention = v
+	}
+	if v := config.RetentionCheckInterval; v > 0 {
+		r.RetentionCheckInterval = v
+	}
+	if v := config.SyncInterval; v > 0 {
+		r.SyncInterval = v
+	}
+	return r, nil
+}
+
+// ValidateConfigCommand implements the "validate-config" subcommand, which
+// runs ReadConfigFile's parsing and validation checks plus a full dry-run
+// construction of every DB and replica (via DBsFromConfig) without starting
+// replication, exiting non-zero if any are reported. It's meant for CI and
+// pre-deploy hooks, where the exit code is the actual signal and a full
+// listing of every problem saves a change/run/change/run loop. Accepts the
+// same repeatable "-o" replica options as the commands that actually run
+// replication, so an option-dependent config (e.g. an invalid file.fsync or
+// s3.force-path-style value supplied on the CLI) validates the same way it
+// would run.
+type ValidateConfigCommand struct{}
+
+func (c *ValidateConfigCommand) Run(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litestream-validate-config", flag.ContinueOnError)
+	var configPath string
+	registerConfigFlag(fs, &configPath)
+	var cliOptions map[string]string
+	registerReplicaOptionsFlag(fs, &cliOptions)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := ReadConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := DBsFromConfig(&config, cliOptions); err != nil {
+		return err
+	}
+
+	fmt.Println("config OK")
+	return nil
+}
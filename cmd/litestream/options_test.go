@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestMergeOptions(t *testing.T) {
+	yamlOptions := map[string]string{"s3.endpoint": "https://example.com", "file.fsync": "true"}
+	cliOptions := map[string]string{"s3.endpoint": "https://override.com"}
+
+	got := mergeOptions(yamlOptions, cliOptions)
+
+	want := map[string]string{"s3.endpoint": "https://override.com", "file.fsync": "true"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d options, want %d", len(got), len(want))
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("got[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestScopedOptions(t *testing.T) {
+	options := map[string]string{"s3.endpoint": "https://example.com", "file.fsync": "true"}
+
+	got := scopedOptions(options, "s3")
+	if len(got) != 1 || got["endpoint"] != "https://example.com" {
+		t.Errorf("scopedOptions(options, %q) = %v, want map with endpoint=https://example.com", "s3", got)
+	}
+}
+
+func TestOptionsFlag_Set(t *testing.T) {
+	f := optionsFlag{}
+	if err := f.Set("s3.endpoint=https://example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if f["s3.endpoint"] != "https://example.com" {
+		t.Errorf("f[%q] = %q, want https://example.com", "s3.endpoint", f["s3.endpoint"])
+	}
+
+	if err := f.Set("invalid-no-equals"); err == nil {
+		t.Fatal("expected error for option missing '='")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateReplica_MissingFields(t *testing.T) {
+	errs := validateReplica("litestream.yml", "/data/a.db", &ReplicaConfig{Type: "s3"})
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2 (region + bucket), errs=%v", len(errs), errs)
+	}
+}
+
+func TestValidateReplica_UnknownType(t *testing.T) {
+	errs := validateReplica("litestream.yml", "/data/a.db", &ReplicaConfig{Type: "nope"})
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1, errs=%v", len(errs), errs)
+	}
+}
+
+func TestValidateReplica_DefaultTypeIsFile(t *testing.T) {
+	errs := validateReplica("litestream.yml", "/data/a.db", &ReplicaConfig{Path: "/backup/a"})
+	if len(errs) != 0 {
+		t.Fatalf("got %d errors, want 0, errs=%v", len(errs), errs)
+	}
+}
+
+func TestValidateConfig_DuplicateDBPath(t *testing.T) {
+	dbs := []*DBConfig{{Path: "/data/a.db"}, {Path: "/data/a.db"}}
+	errs := validateUniqueDBPaths("litestream.yml", dbs)
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1, errs=%v", len(errs), errs)
+	}
+}
+
+func TestConfigErrors_Error(t *testing.T) {
+	errs := ConfigErrors{
+		{File: "litestream.yml", Path: "/data/a.db", Field: "bucket", Err: fmt.Errorf("s3 bucket required")},
+		{File: "litestream.yml", Err: fmt.Errorf("duplicate db path")},
+	}
+
+	msg := errs.Error()
+	if !strings.Contains(msg, "2 config error(s)") {
+		t.Errorf("Error() = %q, want it to report a count of 2", msg)
+	}
+	if !strings.Contains(msg, "s3 bucket required") || !strings.Contains(msg, "duplicate db path") {
+		t.Errorf("Error() = %q, want both underlying messages", msg)
+	}
+}
@@ -0,0 +1,225 @@
+package gcs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/benbjohnson/litestream"
+	"google.golang.org/api/iterator"
+)
+
+// Default replica settings.
+const (
+	DefaultRetention              = 24 * time.Hour
+	DefaultRetentionCheckInterval = 1 * time.Hour
+	DefaultSyncInterval           = 1 * time.Second
+)
+
+// Replica is a client for writing a litestream replica to Google Cloud Storage.
+type Replica struct {
+	db   *litestream.DB
+	name string
+
+	mu     sync.Mutex
+	client *storage.Client
+
+	// Bucket and optional prefix to store files under.
+	Bucket string
+	Path   string
+
+	// Frequency to check for new data to replicate.
+	SyncInterval time.Duration
+
+	// Time to keep snapshots and WAL files before they are allowed to be deleted.
+	Retention time.Duration
+
+	// Time between checks for retention enforcement.
+	RetentionCheckInterval time.Duration
+}
+
+// NewReplica returns a new instance of Replica.
+func NewReplica(db *litestream.DB, name string) *Replica {
+	return &Replica{
+		db:                     db,
+		name:                   name,
+		SyncInterval:           DefaultSyncInterval,
+		Retention:              DefaultRetention,
+		RetentionCheckInterval: DefaultRetentionCheckInterval,
+	}
+}
+
+// Name returns the name of the replica, if set.
+func (r *Replica) Name() string { return r.name }
+
+// DB returns the database the replica is attached to.
+func (r *Replica) DB() *litestream.DB { return r.db }
+
+// Client lazily initializes and returns a GCS client.
+func (r *Replica) Client(ctx context.Context) (*storage.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client != nil {
+		return r.client, nil
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("gcs: cannot create client: %w", err)
+	}
+	r.client = client
+	return r.client, nil
+}
+
+// objectKey returns the full object name for a given relative key, rooted under Path.
+func (r *Replica) objectKey(key string) string {
+	if r.Path == "" {
+		return key
+	}
+	return path.Join(r.Path, key)
+}
+
+// WriteSnapshot uploads a snapshot for generation at index to GCS.
+func (r *Replica) WriteSnapshot(ctx context.Context, generation string, index int, rd io.Reader) error {
+	client, err := r.Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := r.objectKey(path.Join("generations", generation, "snapshots", fmt.Sprintf("%08x.snapshot.lz4", index)))
+	w := client.Bucket(r.Bucket).Object(key).NewWriter(ctx)
+	if _, err := io.Copy(w, rd); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("gcs: cannot write snapshot: %w", err)
+	}
+	return w.Close()
+}
+
+// WriteWALSegment uploads a single WAL segment to GCS.
+func (r *Replica) WriteWALSegment(ctx context.Context, generation string, index int, offset int64, rd io.Reader) error {
+	client, err := r.Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := r.objectKey(path.Join("generations", generation, "wal", fmt.Sprintf("%08x_%08x.wal.lz4", index, offset)))
+	w := client.Bucket(r.Bucket).Object(key).NewWriter(ctx)
+	if _, err := io.Copy(w, rd); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("gcs: cannot write wal segment: %w", err)
+	}
+	return w.Close()
+}
+
+// Generations returns a list of available generation names.
+func (r *Replica) Generations(ctx context.Context) ([]string, error) {
+	client, err := r.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := r.objectKey("generations") + "/"
+	it := client.Bucket(r.Bucket).Objects(ctx, &storage.Query{Prefix: prefix, Delimiter: "/"})
+
+	var generations []string
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("gcs: cannot list generations: %w", err)
+		}
+		if attrs.Prefix == "" {
+			continue
+		}
+		generations = append(generations, strings.Trim(strings.TrimPrefix(attrs.Prefix, prefix), "/"))
+	}
+	return generations, nil
+}
+
+// EnforceRetention removes generations whose newest object is older than Retention.
+func (r *Replica) EnforceRetention(ctx context.Context) error {
+	generations, err := r.Generations(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := r.Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-r.Retention)
+	for _, generation := range generations {
+		prefix := r.objectKey(path.Join("generations", generation)) + "/"
+		it := client.Bucket(r.Bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+		newest := time.Time{}
+		for {
+			attrs, err := it.Next()
+			if err == iterator.Done {
+				break
+			} else if err != nil {
+				return fmt.Errorf("gcs: cannot enumerate generation %s: %w", generation, err)
+			}
+			if attrs.Updated.After(newest) {
+				newest = attrs.Updated
+			}
+		}
+		if newest.Before(cutoff) {
+			if err := r.deletePrefix(ctx, prefix); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deletePrefix removes every object under prefix.
+func (r *Replica) deletePrefix(ctx context.Context, prefix string) error {
+	client, err := r.Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	it := client.Bucket(r.Bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return fmt.Errorf("gcs: cannot list objects for deletion: %w", err)
+		}
+		if err := client.Bucket(r.Bucket).Object(attrs.Name).Delete(ctx); err != nil {
+			return fmt.Errorf("gcs: cannot delete object %s: %w", attrs.Name, err)
+		}
+	}
+	return nil
+}
+
+// WALReader returns a reader for a single WAL segment at the given generation, index, and offset.
+func (r *Replica) WALReader(ctx context.Context, generation string, index int, offset int64) (io.ReadCloser, error) {
+	client, err := r.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := r.objectKey(path.Join("generations", generation, "wal", fmt.Sprintf("%08x_%08x.wal.lz4", index, offset)))
+	return client.Bucket(r.Bucket).Object(key).NewReader(ctx)
+}
+
+// SnapshotReader returns a reader for the snapshot at the given generation and index.
+func (r *Replica) SnapshotReader(ctx context.Context, generation string, index int) (io.ReadCloser, error) {
+	client, err := r.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := r.objectKey(path.Join("generations", generation, "snapshots", fmt.Sprintf("%08x.snapshot.lz4", index)))
+	return client.Bucket(r.Bucket).Object(key).NewReader(ctx)
+}
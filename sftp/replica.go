@@ -0,0 +1,337 @@
+package sftp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Default replica settings.
+const (
+	DefaultRetention              = 24 * time.Hour
+	DefaultRetentionCheckInterval = 1 * time.Hour
+	DefaultSyncInterval           = 1 * time.Second
+)
+
+// Replica is a client for writing a litestream replica to a remote host over SFTP.
+type Replica struct {
+	db   *litestream.DB
+	name string
+
+	mu     sync.Mutex
+	sshCli *ssh.Client
+	client *sftp.Client
+
+	// Connection settings. Host may include a port (host:22); defaults to :22.
+	Host     string
+	User     string
+	Password string
+	KeyPath  string
+
+	// Path to an OpenSSH known_hosts file used to verify the remote host key.
+	// Required: a replica that accepts any host key is open to a trivial
+	// man-in-the-middle of both primary and backup data.
+	KnownHostsPath string
+
+	// Remote directory files are written under.
+	Path string
+
+	// Frequency to check for new data to replicate.
+	SyncInterval time.Duration
+
+	// Time to keep snapshots and WAL files before they are allowed to be deleted.
+	Retention time.Duration
+
+	// Time between checks for retention enforcement.
+	RetentionCheckInterval time.Duration
+}
+
+// NewReplica returns a new instance of Replica.
+func NewReplica(db *litestream.DB, name string) *Replica {
+	return &Replica{
+		db:                     db,
+		name:                   name,
+		SyncInterval:           DefaultSyncInterval,
+		Retention:              DefaultRetention,
+		RetentionCheckInterval: DefaultRetentionCheckInterval,
+	}
+}
+
+// Name returns the name of the replica, if set.
+func (r *Replica) Name() string { return r.name }
+
+// DB returns the database the replica is attached to.
+func (r *Replica) DB() *litestream.DB { return r.db }
+
+// Client lazily dials the remote host and returns an authenticated SFTP client.
+func (r *Replica) Client() (*sftp.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client != nil {
+		return r.client, nil
+	}
+
+	auth, err := r.authMethods()
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeyCallback, err := r.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+
+	addr := r.Host
+	if !strings.Contains(addr, ":") {
+		addr = addr + ":22"
+	}
+
+	sshCli, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            r.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sftp: cannot dial %s: %w", addr, err)
+	}
+
+	client, err := sftp.NewClient(sshCli)
+	if err != nil {
+		_ = sshCli.Close()
+		return nil, fmt.Errorf("sftp: cannot create client: %w", err)
+	}
+
+	r.sshCli, r.client = sshCli, client
+	return r.client, nil
+}
+
+// hostKeyCallback builds the SSH host key verification callback from
+// KnownHostsPath. There is no insecure fallback: KnownHostsPath must be set.
+func (r *Replica) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if r.KnownHostsPath == "" {
+		return nil, fmt.Errorf("sftp: known hosts path required for host key verification")
+	}
+	cb, err := knownhosts.New(r.KnownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: cannot load known hosts file %q: %w", r.KnownHostsPath, err)
+	}
+	return cb, nil
+}
+
+// authMethods builds the SSH auth methods from the configured password or key file.
+func (r *Replica) authMethods() ([]ssh.AuthMethod, error) {
+	if r.KeyPath != "" {
+		buf, err := ioutil.ReadFile(r.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("sftp: cannot read key file: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(buf)
+		if err != nil {
+			return nil, fmt.Errorf("sftp: cannot parse key file: %w", err)
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+	}
+	return []ssh.AuthMethod{ssh.Password(r.Password)}, nil
+}
+
+// Close closes the underlying SFTP and SSH connections.
+func (r *Replica) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client != nil {
+		_ = r.client.Close()
+		r.client = nil
+	}
+	if r.sshCli != nil {
+		err := r.sshCli.Close()
+		r.sshCli = nil
+		return err
+	}
+	return nil
+}
+
+// remotePath returns the full remote path for a given relative key, rooted under Path.
+func (r *Replica) remotePath(key string) string {
+	return path.Join(r.Path, key)
+}
+
+// WriteSnapshot uploads a snapshot for generation at index to the remote host.
+func (r *Replica) WriteSnapshot(ctx context.Context, generation string, index int, rd io.Reader) error {
+	client, err := r.Client()
+	if err != nil {
+		return err
+	}
+
+	name := r.remotePath(path.Join("generations", generation, "snapshots", fmt.Sprintf("%08x.snapshot.lz4", index)))
+	if err := client.MkdirAll(path.Dir(name)); err != nil {
+		return fmt.Errorf("sftp: cannot create directory: %w", err)
+	}
+
+	f, err := client.Create(name)
+	if err != nil {
+		return fmt.Errorf("sftp: cannot create snapshot file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, rd); err != nil {
+		return fmt.Errorf("sftp: cannot write snapshot: %w", err)
+	}
+	return nil
+}
+
+// WriteWALSegment uploads a single WAL segment to the remote host.
+func (r *Replica) WriteWALSegment(ctx context.Context, generation string, index int, offset int64, rd io.Reader) error {
+	client, err := r.Client()
+	if err != nil {
+		return err
+	}
+
+	name := r.remotePath(path.Join("generations", generation, "wal", fmt.Sprintf("%08x_%08x.wal.lz4", index, offset)))
+	if err := client.MkdirAll(path.Dir(name)); err != nil {
+		return fmt.Errorf("sftp: cannot create directory: %w", err)
+	}
+
+	f, err := client.Create(name)
+	if err != nil {
+		return fmt.Errorf("sftp: cannot create wal file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, rd); err != nil {
+		return fmt.Errorf("sftp: cannot write wal segment: %w", err)
+	}
+	return nil
+}
+
+// Generations returns a list of available generation names.
+func (r *Replica) Generations(ctx context.Context) ([]string, error) {
+	client, err := r.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := client.ReadDir(r.remotePath("generations"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("sftp: cannot list generations: %w", err)
+	}
+
+	var generations []string
+	for _, fi := range entries {
+		if fi.IsDir() {
+			generations = append(generations, fi.Name())
+		}
+	}
+	sort.Strings(generations)
+	return generations, nil
+}
+
+// EnforceRetention removes generations whose newest file is older than Retention.
+func (r *Replica) EnforceRetention(ctx context.Context) error {
+	generations, err := r.Generations(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := r.Client()
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-r.Retention)
+	for _, generation := range generations {
+		dir := r.remotePath(path.Join("generations", generation))
+		newest, err := newestModTime(client, dir)
+		if err != nil {
+			return fmt.Errorf("sftp: cannot enumerate generation %s: %w", generation, err)
+		}
+		if newest.Before(cutoff) {
+			if err := removeAll(client, dir); err != nil {
+				return fmt.Errorf("sftp: cannot remove generation %s: %w", generation, err)
+			}
+		}
+	}
+	return nil
+}
+
+// removeAll recursively deletes dir. SFTP's RMDIR, like POSIX rmdir, only
+// succeeds on an empty directory, so every file under dir is removed first,
+// then directories are removed bottom-up.
+func removeAll(client *sftp.Client, dir string) error {
+	var paths []string
+	walker := client.Walk(dir)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+		paths = append(paths, walker.Path())
+	}
+
+	for i := len(paths) - 1; i >= 0; i-- {
+		info, err := client.Stat(paths[i])
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			err = client.RemoveDirectory(paths[i])
+		} else {
+			err = client.Remove(paths[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newestModTime walks dir and returns the most recent modification time found.
+func newestModTime(client *sftp.Client, dir string) (time.Time, error) {
+	var newest time.Time
+	walker := client.Walk(dir)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return newest, err
+		}
+		if info := walker.Stat(); !info.IsDir() && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest, nil
+}
+
+// WALReader returns a reader for a single WAL segment at the given generation, index, and offset.
+func (r *Replica) WALReader(ctx context.Context, generation string, index int, offset int64) (io.ReadCloser, error) {
+	client, err := r.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	name := r.remotePath(path.Join("generations", generation, "wal", fmt.Sprintf("%08x_%08x.wal.lz4", index, offset)))
+	return client.Open(name)
+}
+
+// SnapshotReader returns a reader for the snapshot at the given generation and index.
+func (r *Replica) SnapshotReader(ctx context.Context, generation string, index int) (io.ReadCloser, error) {
+	client, err := r.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	name := r.remotePath(path.Join("generations", generation, "snapshots", fmt.Sprintf("%08x.snapshot.lz4", index)))
+	return client.Open(name)
+}
@@ -0,0 +1,156 @@
+// Package vault resolves credentials stored in HashiCorp Vault for use in
+// litestream replica configuration, via "vault://<path>#<field>" URIs.
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Config holds the settings needed to authenticate against a Vault server,
+// configured under the top-level "vault:" key in litestream.yml.
+type Config struct {
+	Address   string   `yaml:"address"`
+	Token     string   `yaml:"token"`
+	TokenFile string   `yaml:"token-file"`
+	AppRole   *AppRole `yaml:"approle"`
+}
+
+// AppRole holds credentials for Vault's AppRole auth method.
+type AppRole struct {
+	RoleID   string `yaml:"role-id"`
+	SecretID string `yaml:"secret-id"`
+}
+
+// Client resolves vault:// URIs against a Vault server's KV v2 API.
+type Client struct {
+	Config Config
+
+	token string
+}
+
+// NewClient returns a new instance of Client for the given config.
+func NewClient(config Config) *Client {
+	return &Client{Config: config}
+}
+
+// Resolve fetches the secret referenced by a "vault://<path>#<field>" URI,
+// e.g. "vault://secret/data/litestream#secret-access-key".
+func (c *Client) Resolve(rawURI string) (string, error) {
+	u, err := url.Parse(rawURI)
+	if err != nil {
+		return "", fmt.Errorf("vault: invalid uri %q: %w", rawURI, err)
+	} else if u.Scheme != "vault" {
+		return "", fmt.Errorf("vault: not a vault:// uri: %q", rawURI)
+	}
+
+	field := u.Fragment
+	if field == "" {
+		return "", fmt.Errorf("vault: uri %q missing #field", rawURI)
+	}
+	path := strings.TrimPrefix(u.Host+u.Path, "/")
+	if path == "" {
+		return "", fmt.Errorf("vault: uri %q missing secret path", rawURI)
+	}
+
+	token, err := c.authToken()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.url("/v1/"+path), nil)
+	if err != nil {
+		return "", fmt.Errorf("vault: cannot build request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: cannot reach %s: %w", c.Config.Address, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: GET %s returned status %d", path, resp.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("vault: cannot decode response for %s: %w", path, err)
+	}
+
+	v, ok := result.Data.Data[field]
+	if !ok {
+		return "", fmt.Errorf("vault: field %q not found at %s", field, path)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("vault: field %q at %s is not a string", field, path)
+	}
+	return s, nil
+}
+
+// url joins the configured Vault address with a request path.
+func (c *Client) url(p string) string {
+	return strings.TrimSuffix(c.Config.Address, "/") + p
+}
+
+// authToken returns the Vault token to use, resolving an AppRole login or
+// reading TokenFile if Token isn't set directly. The result is cached.
+func (c *Client) authToken() (string, error) {
+	if c.token != "" {
+		return c.token, nil
+	}
+
+	switch {
+	case c.Config.Token != "":
+		c.token = c.Config.Token
+	case c.Config.TokenFile != "":
+		buf, err := ioutil.ReadFile(c.Config.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("vault: cannot read token file: %w", err)
+		}
+		c.token = strings.TrimSpace(string(buf))
+	case c.Config.AppRole != nil:
+		token, err := c.loginAppRole()
+		if err != nil {
+			return "", err
+		}
+		c.token = token
+	default:
+		return "", fmt.Errorf("vault: no token, token-file, or approle configured")
+	}
+	return c.token, nil
+}
+
+// loginAppRole exchanges the configured AppRole role/secret ID for a client token.
+func (c *Client) loginAppRole() (string, error) {
+	body := fmt.Sprintf(`{"role_id":%q,"secret_id":%q}`, c.Config.AppRole.RoleID, c.Config.AppRole.SecretID)
+	resp, err := http.Post(c.url("/v1/auth/approle/login"), "application/json", strings.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("vault: approle login failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: approle login returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("vault: cannot decode approle response: %w", err)
+	}
+	return result.Auth.ClientToken, nil
+}
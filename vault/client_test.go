@@ -0,0 +1,119 @@
+package vault
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestClient_Resolve(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Vault-Token"); got != "s.token" {
+			t.Errorf("X-Vault-Token = %q, want s.token", got)
+		}
+		if r.URL.Path != "/v1/secret/data/litestream" {
+			t.Errorf("path = %q, want /v1/secret/data/litestream", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"data":{"data":{"secret-access-key":"sekrit"}}}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Address: srv.URL, Token: "s.token"})
+	got, err := c.Resolve("vault://secret/data/litestream#secret-access-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "sekrit" {
+		t.Errorf("Resolve() = %q, want sekrit", got)
+	}
+}
+
+func TestClient_Resolve_FieldNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":{"data":{"other-field":"x"}}}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Address: srv.URL, Token: "s.token"})
+	if _, err := c.Resolve("vault://secret/data/litestream#secret-access-key"); err == nil {
+		t.Fatal("expected error for missing field")
+	}
+}
+
+func TestClient_Resolve_NonOKStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Address: srv.URL, Token: "s.token"})
+	if _, err := c.Resolve("vault://secret/data/litestream#secret-access-key"); err == nil {
+		t.Fatal("expected error for non-200 response")
+	}
+}
+
+func TestClient_Resolve_InvalidURI(t *testing.T) {
+	c := NewClient(Config{Address: "http://example.com", Token: "s.token"})
+
+	if _, err := c.Resolve("http://secret/data/litestream#field"); err == nil {
+		t.Fatal("expected error for non-vault scheme")
+	}
+	if _, err := c.Resolve("vault://secret/data/litestream"); err == nil {
+		t.Fatal("expected error for missing #field")
+	}
+	if _, err := c.Resolve("vault://#field"); err == nil {
+		t.Fatal("expected error for missing secret path")
+	}
+}
+
+func TestClient_AuthToken_TokenFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "litestream-vault-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("s.filetoken\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient(Config{TokenFile: f.Name()})
+	token, err := c.authToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "s.filetoken" {
+		t.Errorf("authToken() = %q, want s.filetoken", token)
+	}
+}
+
+func TestClient_AuthToken_NoneConfigured(t *testing.T) {
+	c := NewClient(Config{})
+	if _, err := c.authToken(); err == nil {
+		t.Fatal("expected error when no token, token-file, or approle is configured")
+	}
+}
+
+func TestClient_LoginAppRole(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/approle/login" {
+			t.Errorf("path = %q, want /v1/auth/approle/login", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"auth":{"client_token":"s.approletoken"}}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Address: srv.URL, AppRole: &AppRole{RoleID: "role", SecretID: "secret"}})
+	token, err := c.authToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "s.approletoken" {
+		t.Errorf("authToken() = %q, want s.approletoken", token)
+	}
+}
@@ -0,0 +1,198 @@
+package webdav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/studio-b12/gowebdav"
+)
+
+// Default replica settings.
+const (
+	DefaultRetention              = 24 * time.Hour
+	DefaultRetentionCheckInterval = 1 * time.Hour
+	DefaultSyncInterval           = 1 * time.Second
+)
+
+// Replica is a client for writing a litestream replica to a WebDAV server.
+type Replica struct {
+	db   *litestream.DB
+	name string
+
+	mu     sync.Mutex
+	client *gowebdav.Client
+
+	// Server URL, e.g. https://webdav.example.com/, and optional basic auth.
+	URL      string
+	User     string
+	Password string
+
+	// Remote directory files are written under.
+	Path string
+
+	// Frequency to check for new data to replicate.
+	SyncInterval time.Duration
+
+	// Time to keep snapshots and WAL files before they are allowed to be deleted.
+	Retention time.Duration
+
+	// Time between checks for retention enforcement.
+	RetentionCheckInterval time.Duration
+}
+
+// NewReplica returns a new instance of Replica.
+func NewReplica(db *litestream.DB, name string) *Replica {
+	return &Replica{
+		db:                     db,
+		name:                   name,
+		SyncInterval:           DefaultSyncInterval,
+		Retention:              DefaultRetention,
+		RetentionCheckInterval: DefaultRetentionCheckInterval,
+	}
+}
+
+// Name returns the name of the replica, if set.
+func (r *Replica) Name() string { return r.name }
+
+// DB returns the database the replica is attached to.
+func (r *Replica) DB() *litestream.DB { return r.db }
+
+// Client lazily initializes and returns a WebDAV client.
+func (r *Replica) Client() *gowebdav.Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client == nil {
+		r.client = gowebdav.NewClient(r.URL, r.User, r.Password)
+	}
+	return r.client
+}
+
+// remotePath returns the full remote path for a given relative key, rooted under Path.
+func (r *Replica) remotePath(key string) string {
+	return path.Join(r.Path, key)
+}
+
+// WriteSnapshot uploads a snapshot for generation at index to the WebDAV server.
+func (r *Replica) WriteSnapshot(ctx context.Context, generation string, index int, rd io.Reader) error {
+	client := r.Client()
+
+	name := r.remotePath(path.Join("generations", generation, "snapshots", fmt.Sprintf("%08x.snapshot.lz4", index)))
+	if err := client.MkdirAll(path.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("webdav: cannot create directory: %w", err)
+	}
+	if err := client.WriteStream(name, rd, 0o644); err != nil {
+		return fmt.Errorf("webdav: cannot write snapshot: %w", err)
+	}
+	return nil
+}
+
+// WriteWALSegment uploads a single WAL segment to the WebDAV server.
+func (r *Replica) WriteWALSegment(ctx context.Context, generation string, index int, offset int64, rd io.Reader) error {
+	client := r.Client()
+
+	name := r.remotePath(path.Join("generations", generation, "wal", fmt.Sprintf("%08x_%08x.wal.lz4", index, offset)))
+	if err := client.MkdirAll(path.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("webdav: cannot create directory: %w", err)
+	}
+	if err := client.WriteStream(name, rd, 0o644); err != nil {
+		return fmt.Errorf("webdav: cannot write wal segment: %w", err)
+	}
+	return nil
+}
+
+// Generations returns a list of available generation names.
+func (r *Replica) Generations(ctx context.Context) ([]string, error) {
+	client := r.Client()
+
+	entries, err := client.ReadDir(r.remotePath("generations"))
+	if _, ok := err.(*os.PathError); ok {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("webdav: cannot list generations: %w", err)
+	}
+
+	var generations []string
+	for _, fi := range entries {
+		if fi.IsDir() {
+			generations = append(generations, fi.Name())
+		}
+	}
+	sort.Strings(generations)
+	return generations, nil
+}
+
+// EnforceRetention removes generations whose newest file is older than Retention.
+func (r *Replica) EnforceRetention(ctx context.Context) error {
+	generations, err := r.Generations(ctx)
+	if err != nil {
+		return err
+	}
+
+	client := r.Client()
+	cutoff := time.Now().Add(-r.Retention)
+	for _, generation := range generations {
+		dir := r.remotePath(path.Join("generations", generation))
+		newest, err := newestModTime(client, dir)
+		if err != nil {
+			return fmt.Errorf("webdav: cannot enumerate generation %s: %w", generation, err)
+		}
+		if newest.Before(cutoff) {
+			if err := client.RemoveAll(dir); err != nil {
+				return fmt.Errorf("webdav: cannot remove generation %s: %w", generation, err)
+			}
+		}
+	}
+	return nil
+}
+
+// newestModTime recursively walks dir and returns the most recent
+// modification time among the files under it (snapshots and WAL segments
+// live two levels down, under "snapshots/" and "wal/", so dir's own mtime
+// never reflects new data landing underneath it).
+func newestModTime(client *gowebdav.Client, dir string) (time.Time, error) {
+	entries, err := client.ReadDir(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var newest time.Time
+	for _, fi := range entries {
+		p := path.Join(dir, fi.Name())
+		if fi.IsDir() {
+			t, err := newestModTime(client, p)
+			if err != nil {
+				return time.Time{}, err
+			}
+			if t.After(newest) {
+				newest = t
+			}
+			continue
+		}
+		if fi.ModTime().After(newest) {
+			newest = fi.ModTime()
+		}
+	}
+	return newest, nil
+}
+
+// WALReader returns a reader for a single WAL segment at the given generation, index, and offset.
+func (r *Replica) WALReader(ctx context.Context, generation string, index int, offset int64) (io.ReadCloser, error) {
+	client := r.Client()
+	name := r.remotePath(path.Join("generations", generation, "wal", fmt.Sprintf("%08x_%08x.wal.lz4", index, offset)))
+	return client.ReadStream(name)
+}
+
+// SnapshotReader returns a reader for the snapshot at the given generation and index.
+func (r *Replica) SnapshotReader(ctx context.Context, generation string, index int) (io.ReadCloser, error) {
+	client := r.Client()
+	name := r.remotePath(path.Join("generations", generation, "snapshots", fmt.Sprintf("%08x.snapshot.lz4", index)))
+	return client.ReadStream(name)
+}
@@ -0,0 +1,102 @@
+package webdav
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// propfindResponse renders a minimal WebDAV multistatus response listing the
+// collection at href itself first (as a server does, and as gowebdav's
+// ReadDir expects and skips), then the given entries directly under href.
+func propfindResponse(href string, entries []struct {
+	name    string
+	isDir   bool
+	modTime time.Time
+}) string {
+	body := `<?xml version="1.0"?><D:multistatus xmlns:D="DAV:">`
+	body += fmt.Sprintf(`<D:response>
+		<D:href>%s/</D:href>
+		<D:propstat>
+			<D:prop>
+				<D:resourcetype><D:collection/></D:resourcetype>
+			</D:prop>
+			<D:status>HTTP/1.1 200 OK</D:status>
+		</D:propstat>
+	</D:response>`, href)
+	for _, e := range entries {
+		resourceType := ""
+		if e.isDir {
+			resourceType = "<D:collection/>"
+		}
+		body += fmt.Sprintf(`<D:response>
+			<D:href>%s/%s</D:href>
+			<D:propstat>
+				<D:prop>
+					<D:resourcetype>%s</D:resourcetype>
+					<D:getlastmodified>%s</D:getlastmodified>
+				</D:prop>
+				<D:status>HTTP/1.1 200 OK</D:status>
+			</D:propstat>
+		</D:response>`, href, e.name, resourceType, e.modTime.UTC().Format(http.TimeFormat))
+	}
+	body += `</D:multistatus>`
+	return body
+}
+
+// TestNewestModTime_RecursesIntoSubdirectories guards against the retention
+// bug where only the top-level generation directory's own mtime was checked:
+// snapshots/ and wal/ live two levels down, so the newest file must be found
+// by walking into them, not by stat-ing the generation directory itself.
+func TestNewestModTime_RecursesIntoSubdirectories(t *testing.T) {
+	oldTime := time.Now().Add(-48 * time.Hour)
+	newTime := time.Now().Add(-1 * time.Minute)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gen1/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, propfindResponse("/gen1", []struct {
+			name    string
+			isDir   bool
+			modTime time.Time
+		}{
+			{name: "snapshots", isDir: true, modTime: oldTime},
+			{name: "wal", isDir: true, modTime: oldTime},
+		}))
+	})
+	mux.HandleFunc("/gen1/snapshots/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, propfindResponse("/gen1/snapshots", []struct {
+			name    string
+			isDir   bool
+			modTime time.Time
+		}{
+			{name: "00000000.snapshot.lz4", isDir: false, modTime: oldTime},
+		}))
+	})
+	mux.HandleFunc("/gen1/wal/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, propfindResponse("/gen1/wal", []struct {
+			name    string
+			isDir   bool
+			modTime time.Time
+		}{
+			{name: "00000000_00000000.wal.lz4", isDir: false, modTime: newTime},
+		}))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	r := &Replica{URL: srv.URL}
+	newest, err := newestModTime(r.Client(), "/gen1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if newest.Before(newTime.Add(-time.Second)) {
+		t.Fatalf("newestModTime = %v, want at or after the freshly-written wal segment at %v (nested wal/ mtime was not picked up)", newest, newTime)
+	}
+}